@@ -1,22 +1,33 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
 	"io"
 	"log"
+	"net"
 	"net/http"
+	"net/smtp"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/gorilla/websocket"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/AzzurroTech/POD/store"
+	"github.com/AzzurroTech/POD/store/sqlite"
 )
 
 /*
@@ -28,43 +39,23 @@ import (
 /* ----------------------------------------------------------------------
    1️⃣  GLOBAL STATE
    ---------------------------------------------------------------------- */
-type userRec struct {
-	Salt          []byte // 16‑byte random salt (plain)
-	PassHash      []byte // SHA‑256(salt‖password)
-	EncContextB64 string // base64‑encoded AES‑GCM ciphertext of the UI context
-}
-
-// In‑memory user DB and session store
-var (
-	mu          sync.RWMutex
-	users       = make(map[string]*userRec) // username → record
-	sessions    = make(map[string]string)   // sessionID → username (empty = guest)
-	nextSessNum int64 = 1
-)
+// db is the persistence backend for users, sessions, stored forms, and
+// imported templates; main() wires it up to a sqlite.Store before any
+// handler runs.
+var db store.Store
 
-// Key/value maps used by the original form‑storage logic
-var (
-	keyToFiles   = make(map[string][]string) // key   → []filenames
-	valueToFiles = make(map[string][]string) // value → []filenames
-	storedFiles  []string                    // ordered list of filenames (no .html)
-	storageDir   = "./forms"                 // where tiny HTML files are written
-)
-
-// In‑memory storage for imported HTML templates
-var (
-	templatesMu sync.RWMutex
-	templates   = make(map[string]string) // filename → raw HTML (includes <template> wrapper)
-)
+// storageDir is where the tiny per-submission HTML files written by
+// writeFormFile live; it's unrelated to db, which only tracks the index.
+var storageDir = "./forms"
 
 /* ----------------------------------------------------------------------
    2️⃣  SESSION & CRYPTO HELPERS
    ---------------------------------------------------------------------- */
 func newSession(username string) string {
-	mu.Lock()
-	defer mu.Unlock()
-	id := fmt.Sprintf("%d-%d", time.Now().UnixNano(), nextSessNum)
-	nextSessNum++
-	sessions[id] = username
+	id, err := db.NewSession(username)
+	if err != nil {
+		log.Printf("newSession: %v", err)
+	}
 	return id
 }
 
@@ -73,9 +64,15 @@ func getUsername(r *http.Request) string {
 	if err != nil {
 		return ""
 	}
-	mu.RLock()
-	defer mu.RUnlock()
-	return sessions[c.Value]
+	username, ok, err := db.LookupSession(c.Value)
+	if err != nil {
+		log.Printf("getUsername: %v", err)
+		return ""
+	}
+	if !ok {
+		return ""
+	}
+	return username
 }
 
 func setSIDCookie(w http.ResponseWriter, sid string) {
@@ -90,6 +87,19 @@ func setSIDCookie(w http.ResponseWriter, sid string) {
 }
 
 // ---- Password handling -------------------------------------------------
+const bcryptPrefix = "bcrypt$"
+
+// bcryptCost returns the configured bcrypt cost factor, defaulting to 12.
+// Override with the POD_BCRYPT_COST env var.
+func bcryptCost() int {
+	if v := os.Getenv("POD_BCRYPT_COST"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= bcrypt.MinCost && n <= bcrypt.MaxCost {
+			return n
+		}
+	}
+	return 12
+}
+
 func genSalt() []byte {
 	b := make([]byte, 16)
 	if _, err := rand.Read(b); err != nil {
@@ -97,6 +107,9 @@ func genSalt() []byte {
 	}
 	return b
 }
+
+// hashPassword is the legacy SHA‑256(salt‖password) scheme, kept only so
+// existing accounts created before bcrypt support can still log in.
 func hashPassword(salt []byte, password string) []byte {
 	h := sha256.New()
 	h.Write(salt)
@@ -104,6 +117,28 @@ func hashPassword(salt []byte, password string) []byte {
 	return h.Sum(nil)
 }
 
+// hashPasswordBcrypt hashes password at the configured cost and returns the
+// "bcrypt$"-prefixed PassHash to store on the user record.
+func hashPasswordBcrypt(password string) ([]byte, error) {
+	h, err := bcrypt.GenerateFromPassword([]byte(password), bcryptCost())
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(bcryptPrefix), h...), nil
+}
+
+// VerifyPassword checks pw against rec under whichever scheme rec.PassHash
+// was stored with. needsUpgrade is true whenever rec.PassHash uses the
+// legacy SHA‑256 scheme, whether or not pw matched; loginHandler rehashes
+// pw with bcrypt only when ok is also true.
+func VerifyPassword(rec *store.User, pw string) (ok, needsUpgrade bool) {
+	if bytes.HasPrefix(rec.PassHash, []byte(bcryptPrefix)) {
+		stored := bytes.TrimPrefix(rec.PassHash, []byte(bcryptPrefix))
+		return bcrypt.CompareHashAndPassword(stored, []byte(pw)) == nil, false
+	}
+	return bytes.Equal(rec.PassHash, hashPassword(rec.Salt, pw)), true
+}
+
 /* ----------------------------------------------------------------------
    3️⃣  TEMPLATE VARIABLES (filled at startup)
    ---------------------------------------------------------------------- */
@@ -111,8 +146,60 @@ var (
 	loginTmpl    *template.Template
 	registerTmpl *template.Template
 	appTmpl      *template.Template
+	forgotPwTmpl *template.Template
+	resetPwTmpl  *template.Template
+	notFoundTmpl *template.Template
 )
 
+// pageTmplMu guards the page-template vars above against concurrent
+// reload by templateWatcher.
+var pageTmplMu sync.RWMutex
+
+func execLoginTmpl(w http.ResponseWriter, data interface{}) error {
+	pageTmplMu.RLock()
+	t := loginTmpl
+	pageTmplMu.RUnlock()
+	return t.Execute(w, data)
+}
+
+func execRegisterTmpl(w http.ResponseWriter, data interface{}) error {
+	pageTmplMu.RLock()
+	t := registerTmpl
+	pageTmplMu.RUnlock()
+	return t.Execute(w, data)
+}
+
+func execAppTmpl(w http.ResponseWriter, data interface{}) error {
+	pageTmplMu.RLock()
+	t := appTmpl
+	pageTmplMu.RUnlock()
+	return t.Execute(w, data)
+}
+
+func execForgotPwTmpl(w http.ResponseWriter, data interface{}) error {
+	pageTmplMu.RLock()
+	t := forgotPwTmpl
+	pageTmplMu.RUnlock()
+	return t.Execute(w, data)
+}
+
+func execResetPwTmpl(w http.ResponseWriter, data interface{}) error {
+	pageTmplMu.RLock()
+	t := resetPwTmpl
+	pageTmplMu.RUnlock()
+	return t.Execute(w, data)
+}
+
+func execNotFoundTmpl(w http.ResponseWriter, data interface{}) error {
+	pageTmplMu.RLock()
+	t := notFoundTmpl
+	pageTmplMu.RUnlock()
+	if t == nil {
+		return nil
+	}
+	return t.Execute(w, data)
+}
+
 /* ----------------------------------------------------------------------
    4️⃣  TEMPLATE LOADING (executed once in main)
    ---------------------------------------------------------------------- */
@@ -130,6 +217,18 @@ func loadTemplates() error {
 	if err != nil {
 		return fmt.Errorf("loading app.html: %w", err)
 	}
+	forgotPwTmpl, err = template.ParseFiles(filepath.Join("templates", "forgotpw.html"))
+	if err != nil {
+		return fmt.Errorf("loading forgotpw.html: %w", err)
+	}
+	resetPwTmpl, err = template.ParseFiles(filepath.Join("templates", "resetpw.html"))
+	if err != nil {
+		return fmt.Errorf("loading resetpw.html: %w", err)
+	}
+	notFoundTmpl, err = template.ParseFiles(filepath.Join("templates", "404.html"))
+	if err != nil {
+		return fmt.Errorf("loading 404.html: %w", err)
+	}
 	return nil
 }
 
@@ -148,7 +247,7 @@ func loginHandler(w http.ResponseWriter, r *http.Request) {
 	case http.MethodGet:
 		// Preserve any original query string so we can forward it after login
 		redirect := r.URL.RawQuery
-		loginTmpl.Execute(w, map[string]string{
+		execLoginTmpl(w, map[string]string{
 			"Error":    "",
 			"Redirect": redirect,
 		})
@@ -161,17 +260,33 @@ func loginHandler(w http.ResponseWriter, r *http.Request) {
 		password := r.FormValue("password")
 		redirect := r.FormValue("redirect")
 
-		mu.RLock()
-		rec, ok := users[username]
-		mu.RUnlock()
-		if !ok || !bytes.Equal(rec.PassHash, hashPassword(rec.Salt, password)) {
+		rec, ok, err := db.GetUser(username)
+		if err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		var valid, needsUpgrade bool
+		if ok {
+			valid, needsUpgrade = VerifyPassword(rec, password)
+		}
+		if !valid {
 			// Invalid credentials – redisplay login with error
-			loginTmpl.Execute(w, map[string]string{
+			execLoginTmpl(w, map[string]string{
 				"Error":    "Invalid credentials",
 				"Redirect": redirect,
 			})
 			return
 		}
+		if needsUpgrade {
+			// Transparently migrate this account off the legacy SHA‑256 scheme.
+			if newHash, err := hashPasswordBcrypt(password); err == nil {
+				if err := db.UpdateUserPassword(username, rec.Salt, newHash); err != nil {
+					log.Printf("login: bcrypt upgrade failed for %s: %v", username, err)
+				}
+			} else {
+				log.Printf("login: bcrypt upgrade failed for %s: %v", username, err)
+			}
+		}
 		// Successful login
 		sid := newSession(username)
 		setSIDCookie(w, sid)
@@ -191,7 +306,7 @@ func loginHandler(w http.ResponseWriter, r *http.Request) {
 func registerHandler(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodGet:
-		registerTmpl.Execute(w, map[string]string{"Error": ""})
+		execRegisterTmpl(w, map[string]string{"Error": ""})
 	case http.MethodPost:
 		if err := r.ParseForm(); err != nil {
 			http.Error(w, "bad form", http.StatusBadRequest)
@@ -200,29 +315,31 @@ func registerHandler(w http.ResponseWriter, r *http.Request) {
 		username := r.FormValue("username")
 		password := r.FormValue("password")
 
-		mu.Lock()
-		if _, exists := users[username]; exists {
-			mu.Unlock()
-			registerTmpl.Execute(w, map[string]string{"Error": "Username already taken"})
+		passHash, err := hashPasswordBcrypt(password)
+		if err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
 			return
 		}
+
 		salt := genSalt()
-		rec := &userRec{
-			Salt:     salt,
-			PassHash: hashPassword(salt, password),
+		if err := db.CreateUser(username, salt, passHash); err != nil {
+			if errors.Is(err, store.ErrUserExists) {
+				execRegisterTmpl(w, map[string]string{"Error": "Username already taken"})
+				return
+			}
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
 		}
-		users[username] = rec
-		mu.Unlock()
 
 		// Auto‑login after registration
 		sid := newSession(username)
 		setSIDCookie(w, sid)
 
 		// Render the main UI (no saved context yet)
-		appTmpl.Execute(w, map[string]string{
-			"Username":   username,
-			"SaltB64":    base64.StdEncoding.EncodeToString(salt),
-			"EncCtxB64":  "",
+		execAppTmpl(w, map[string]string{
+			"Username":  username,
+			"SaltB64":   base64.StdEncoding.EncodeToString(salt),
+			"EncCtxB64": "",
 			"Bypass":    "0",
 		})
 	default:
@@ -234,9 +351,9 @@ func registerHandler(w http.ResponseWriter, r *http.Request) {
 func logoutHandler(w http.ResponseWriter, r *http.Request) {
 	c, err := r.Cookie("sid")
 	if err == nil {
-		mu.Lock()
-		delete(sessions, c.Value)
-		mu.Unlock()
+		if err := db.DeleteSession(c.Value); err != nil {
+			log.Printf("logout: %v", err)
+		}
 		// Expire the cookie
 		http.SetCookie(w, &http.Cookie{
 			Name:   "sid",
@@ -272,19 +389,17 @@ func appHandler(w http.ResponseWriter, r *http.Request) {
 	// Pull user record (if any) to get salt & encrypted context
 	var saltB64, encB64 string
 	if username != "" {
-		mu.RLock()
-		if rec, ok := users[username]; ok {
+		if rec, ok, err := db.GetUser(username); err == nil && ok {
 			saltB64 = base64.StdEncoding.EncodeToString(rec.Salt)
 			encB64 = rec.EncContextB64
 		}
-		mu.RUnlock()
 	}
 
 	// Render the Combined‑UX page
-	appTmpl.Execute(w, map[string]string{
-		"Username":   username,
-		"SaltB64":    saltB64,
-		"EncCtxB64":  encB64,
+	execAppTmpl(w, map[string]string{
+		"Username":  username,
+		"SaltB64":   saltB64,
+		"EncCtxB64": encB64,
 		"Bypass":    strconv.FormatBool(bypass),
 	})
 }
@@ -308,11 +423,9 @@ func saveContextHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "bad json", http.StatusBadRequest)
 		return
 	}
-	mu.Lock()
-	if rec, ok := users[username]; ok {
-		rec.EncContextB64 = payload.Enc
+	if err := db.UpdateUserContext(username, payload.Enc); err != nil {
+		log.Printf("saveContext: %v", err)
 	}
-	mu.Unlock()
 	w.WriteHeader(http.StatusOK)
 }
 
@@ -339,7 +452,11 @@ func queryHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "failed to write file", http.StatusInternalServerError)
 		return
 	}
-	indexFile(base, formVals)
+	if err := db.InsertForm(base, formVals); err != nil {
+		http.Error(w, "failed to index file", http.StatusInternalServerError)
+		return
+	}
+	formBroker.Publish(Event{Type: "form", Base: base, Keys: keysOf(formVals), Values: valuesOf(formVals)})
 
 	// Respond with JSON
 	w.Header().Set("Content-Type", "application/json")
@@ -370,12 +487,20 @@ func importHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "cannot read uploaded file", http.StatusInternalServerError)
 		return
 	}
-	// Wrap the raw HTML in a <template> tag and store it in memory
+	// Wrap the raw HTML in a <template> tag and store it
 	wrapped := fmt.Sprintf("<template data-name=\"%s\">\n%s\n</template>", hdr.Filename, string(content))
+	if err := db.PutTemplate(hdr.Filename, wrapped); err != nil {
+		http.Error(w, "failed to store template", http.StatusInternalServerError)
+		return
+	}
 
-	templatesMu.Lock()
-	templates[hdr.Filename] = wrapped
-	templatesMu.Unlock()
+	// Persist the raw upload to disk so it survives a restart and so
+	// templateWatcher picks up any further external edits to it.
+	if err := os.MkdirAll(importedDir, 0755); err != nil {
+		log.Printf("imported: cannot create %s: %v", importedDir, err)
+	} else if err := os.WriteFile(filepath.Join(importedDir, hdr.Filename), content, 0644); err != nil {
+		log.Printf("imported: cannot persist %s: %v", hdr.Filename, err)
+	}
 
 	// Respond with JSON so the client can refresh its UI
 	w.Header().Set("Content-Type", "application/json")
@@ -384,12 +509,11 @@ func importHandler(w http.ResponseWriter, r *http.Request) {
 
 // ----- Serve the list of template filenames (manifest) ---------------
 func templatesManifestHandler(w http.ResponseWriter, r *http.Request) {
-	templatesMu.RLock()
-	names := make([]string, 0, len(templates))
-	for n := range templates {
-		names = append(names, n)
+	names, err := db.ListTemplates()
+	if err != nil {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
 	}
-	templatesMu.RUnlock()
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(names)
 }
@@ -404,9 +528,11 @@ func templateFileHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	filename := parts[2]
 
-	templatesMu.RLock()
-	data, ok := templates[filename]
-	templatesMu.RUnlock()
+	data, ok, err := db.GetTemplate(filename)
+	if err != nil {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
 	if !ok {
 		http.NotFound(w, r)
 		return
@@ -447,66 +573,836 @@ func writeFormFile(values map[string][]string) (string, error) {
 	return base, nil // return the base name (without .html)
 }
 
-func indexFile(baseName string, values map[string][]string) {
-	mu.Lock()
-	defer mu.Unlock()
-	storedFiles = append(storedFiles, baseName)
+/* ----------------------------------------------------------------------
+   7️⃣  PASSWORD RESET ("FORGOT PASSWORD") FLOW
+   ---------------------------------------------------------------------- */
+const (
+	resetTokenTTL = time.Hour        // how long a reset link stays valid
+	resetWaitTime = 10 * time.Minute // min. gap between reset emails per user
+)
+
+// pwresetFile and pwresetWaitFile are vars, like emailSender, so tests can
+// point them at a scratch directory instead of the real ./data.
+var (
+	pwresetFile     = "./data/pwreset.tsv"
+	pwresetWaitFile = "./data/pwreset_wait.tsv"
+)
+
+// EmailSender abstracts outgoing mail delivery so tests can substitute a
+// fake implementation instead of talking to a real SMTP server.
+type EmailSender interface {
+	Send(to, subject, body string) error
+}
+
+// SMTPEmailSender delivers mail through a plain SMTP relay.
+type SMTPEmailSender struct {
+	Addr string // host:port of the relay
+	From string
+	Auth smtp.Auth
+}
+
+func (s *SMTPEmailSender) Send(to, subject, body string) error {
+	msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\n\r\n%s\r\n", s.From, to, subject, body)
+	return smtp.SendMail(s.Addr, s.Auth, s.From, []string{to}, []byte(msg))
+}
 
-	for k, vals := range values {
-		k = strings.TrimSpace(k)
-		if k == "" {
+// emailSender is swapped out in tests; main() leaves this default SMTP
+// relay on localhost:25 in place.
+var emailSender EmailSender = &SMTPEmailSender{Addr: "localhost:25", From: "noreply@pod.local"}
+
+// pwresetMu serializes reads/writes of the reset TSV files so concurrent
+// requests can't interleave an append with a rewrite.
+var pwresetMu sync.Mutex
+
+type resetEntry struct {
+	Token     string
+	Username  string
+	CreatedAt time.Time
+}
+
+func appendTSV(path string, fields ...string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = fmt.Fprintln(f, strings.Join(fields, "\t"))
+	return err
+}
+
+func readTSVLines(path string) ([][]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var rows [][]string
+	for _, line := range strings.Split(strings.TrimRight(string(data), "\n"), "\n") {
+		if line == "" {
 			continue
 		}
-		keyToFiles[k] = append(keyToFiles[k], baseName)
+		rows = append(rows, strings.Split(line, "\t"))
+	}
+	return rows, nil
+}
 
-		for _, v := range vals {
-			v = strings.TrimSpace(v)
-			if v == "" {
+func generateResetToken() (string, error) {
+	b := make([]byte, 64)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.URLEncoding.EncodeToString(b), nil
+}
+
+// lastResetRequest returns the time of the most recent reset request for
+// username, or the zero Time if none is on record.
+func lastResetRequest(username string) (time.Time, error) {
+	rows, err := readTSVLines(pwresetWaitFile)
+	if err != nil {
+		return time.Time{}, err
+	}
+	var last time.Time
+	for _, row := range rows {
+		if len(row) != 2 || row[0] != username {
+			continue
+		}
+		if ts, err := strconv.ParseInt(row[1], 10, 64); err == nil {
+			if t := time.Unix(0, ts); t.After(last) {
+				last = t
+			}
+		}
+	}
+	return last, nil
+}
+
+// lookupResetEntry returns the most recent on-disk entry for token, or
+// nil if none exists.
+func lookupResetEntry(token string) (*resetEntry, error) {
+	rows, err := readTSVLines(pwresetFile)
+	if err != nil {
+		return nil, err
+	}
+	for i := len(rows) - 1; i >= 0; i-- {
+		row := rows[i]
+		if len(row) != 3 || row[0] != token {
+			continue
+		}
+		ts, err := strconv.ParseInt(row[2], 10, 64)
+		if err != nil {
+			continue
+		}
+		return &resetEntry{Token: row[0], Username: row[1], CreatedAt: time.Unix(0, ts)}, nil
+	}
+	return nil, nil
+}
+
+// consumeResetToken rewrites pwresetFile without the given token so it
+// cannot be replayed.
+func consumeResetToken(token string) error {
+	rows, err := readTSVLines(pwresetFile)
+	if err != nil {
+		return err
+	}
+	var kept []string
+	for _, row := range rows {
+		if len(row) == 3 && row[0] == token {
+			continue
+		}
+		kept = append(kept, strings.Join(row, "\t"))
+	}
+	tmp := pwresetFile + ".tmp"
+	if err := os.WriteFile(tmp, []byte(strings.Join(kept, "\n")+"\n"), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, pwresetFile)
+}
+
+// pruneExpiredTSV drops every row of path whose timestamp field (at index
+// tsField) is older than ttl, keeping both reset TSV files from growing
+// without bound as they're rescanned on every request.
+func pruneExpiredTSV(path string, ttl time.Duration, tsField int) error {
+	rows, err := readTSVLines(path)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	var kept []string
+	for _, row := range rows {
+		if len(row) <= tsField {
+			continue
+		}
+		ts, err := strconv.ParseInt(row[tsField], 10, 64)
+		if err != nil || now.Sub(time.Unix(0, ts)) > ttl {
+			continue
+		}
+		kept = append(kept, strings.Join(row, "\t"))
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(strings.Join(kept, "\n")+"\n"), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// invalidateSessions drops every session belonging to username, forcing
+// re-authentication after a credential change.
+func invalidateSessions(username string) {
+	if err := db.DeleteSessionsForUser(username); err != nil {
+		log.Printf("invalidateSessions: %v", err)
+	}
+}
+
+// requestScheme returns "https" if r arrived over TLS (directly, or via a
+// reverse proxy that set X-Forwarded-Proto) and "http" otherwise. pod
+// runs with TLS only when POD_TLS_CERT/POD_TLS_KEY are set, so reset links
+// built from a hardcoded "https://" would be broken in plain-HTTP mode.
+func requestScheme(r *http.Request) string {
+	if r.TLS != nil {
+		return "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+		return proto
+	}
+	return "http"
+}
+
+// ----- Forgot password: GET renders the request form, POST emails a reset link.
+func passwordResetRequestHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		execForgotPwTmpl(w, map[string]string{"Error": "", "Sent": ""})
+	case http.MethodPost:
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "bad form", http.StatusBadRequest)
+			return
+		}
+		username := r.FormValue("username")
+
+		_, exists, err := db.GetUser(username)
+		if err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		// Report success either way so we don't leak which usernames exist.
+		if !exists {
+			execForgotPwTmpl(w, map[string]string{"Error": "", "Sent": "1"})
+			return
+		}
+
+		pwresetMu.Lock()
+		defer pwresetMu.Unlock()
+
+		// Keep both TSV files from growing without bound, since they're
+		// rescanned in full on every request.
+		if err := pruneExpiredTSV(pwresetFile, resetTokenTTL, 2); err != nil {
+			log.Printf("password reset: prune %s: %v", pwresetFile, err)
+		}
+		if err := pruneExpiredTSV(pwresetWaitFile, resetWaitTime, 1); err != nil {
+			log.Printf("password reset: prune %s: %v", pwresetWaitFile, err)
+		}
+
+		if last, err := lastResetRequest(username); err == nil && time.Since(last) < resetWaitTime {
+			execForgotPwTmpl(w, map[string]string{"Error": "Please wait before requesting another reset email.", "Sent": ""})
+			return
+		}
+
+		token, err := generateResetToken()
+		if err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		now := time.Now()
+		if err := appendTSV(pwresetFile, token, username, strconv.FormatInt(now.UnixNano(), 10)); err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		if err := appendTSV(pwresetWaitFile, username, strconv.FormatInt(now.UnixNano(), 10)); err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		link := fmt.Sprintf("%s://%s/resetPassword?token=%s", requestScheme(r), r.Host, token)
+		body := fmt.Sprintf("Use the link below within %s to set a new password:\n\n%s\n", resetTokenTTL, link)
+		if err := emailSender.Send(username, "Reset your POD password", body); err != nil {
+			log.Printf("password reset: failed to email %s: %v", username, err)
+		}
+
+		execForgotPwTmpl(w, map[string]string{"Error": "", "Sent": "1"})
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// ----- Password reset: GET validates the token, POST applies the new password.
+func passwordResetHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		token := r.URL.Query().Get("token")
+		entry, err := lookupResetEntry(token)
+		if err != nil || entry == nil || time.Since(entry.CreatedAt) > resetTokenTTL {
+			execResetPwTmpl(w, map[string]string{"Error": "This reset link is invalid or has expired.", "Token": ""})
+			return
+		}
+		execResetPwTmpl(w, map[string]string{"Error": "", "Token": token})
+	case http.MethodPost:
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "bad form", http.StatusBadRequest)
+			return
+		}
+		token := r.FormValue("token")
+		password := r.FormValue("password")
+
+		pwresetMu.Lock()
+		entry, err := lookupResetEntry(token)
+		if err != nil || entry == nil || time.Since(entry.CreatedAt) > resetTokenTTL {
+			pwresetMu.Unlock()
+			execResetPwTmpl(w, map[string]string{"Error": "This reset link is invalid or has expired.", "Token": ""})
+			return
+		}
+		if err := consumeResetToken(token); err != nil {
+			pwresetMu.Unlock()
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		pwresetMu.Unlock()
+
+		passHash, err := hashPasswordBcrypt(password)
+		if err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		if _, ok, err := db.GetUser(entry.Username); err != nil || !ok {
+			http.Error(w, "unknown user", http.StatusInternalServerError)
+			return
+		}
+		if err := db.UpdateUserPassword(entry.Username, genSalt(), passHash); err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		invalidateSessions(entry.Username)
+
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+/* ----------------------------------------------------------------------
+   8️⃣  SECURITY MIDDLEWARE & TLS STARTUP
+   ---------------------------------------------------------------------- */
+const cspHeader = "default-src 'self'; img-src data: 'self'; connect-src ws: wss: 'self'"
+
+// mungeResponseWriter lets mungeHandler intercept a 404 response and
+// substitute our own rendered not-found page for the handler's body.
+type mungeResponseWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+	suppress    bool
+}
+
+func (m *mungeResponseWriter) WriteHeader(status int) {
+	if m.wroteHeader {
+		return
+	}
+	m.wroteHeader = true
+	if status == http.StatusNotFound {
+		m.suppress = true
+	}
+	m.ResponseWriter.WriteHeader(status)
+}
+
+func (m *mungeResponseWriter) Write(b []byte) (int, error) {
+	if !m.wroteHeader {
+		m.WriteHeader(http.StatusOK)
+	}
+	if m.suppress {
+		// Discard the handler's own 404 body; we render our own below.
+		return len(b), nil
+	}
+	return m.ResponseWriter.Write(b)
+}
+
+// Hijack passes through to the wrapped ResponseWriter so wsHandler can
+// still upgrade the connection when run behind mungeHandler.
+func (m *mungeResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := m.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("mungeResponseWriter: underlying ResponseWriter does not support hijacking")
+	}
+	return hj.Hijack()
+}
+
+// mungeHandler adds the CSP/security headers every response should carry
+// and swaps in templates/404.html whenever the wrapped handler answers
+// with a 404.
+func mungeHandler(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		h := w.Header()
+		h.Set("Content-Security-Policy", cspHeader)
+		h.Set("X-Content-Type-Options", "nosniff")
+		h.Set("Referrer-Policy", "same-origin")
+
+		mw := &mungeResponseWriter{ResponseWriter: w}
+		next(mw, r)
+		if mw.suppress {
+			execNotFoundTmpl(w, nil)
+		}
+	}
+}
+
+// startServer runs the HTTP server on addr, upgrading to TLS when both
+// tlsCert and tlsKey are non-empty.
+func startServer(addr, tlsCert, tlsKey string) error {
+	srv := &http.Server{
+		Addr:              addr,
+		IdleTimeout:       120 * time.Second,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	if tlsCert != "" && tlsKey != "" {
+		log.Printf("🚀 Server listening on https://%s/app", addr)
+		return srv.ListenAndServeTLS(tlsCert, tlsKey)
+	}
+	log.Printf("🚀 Server listening on http://%s/app", addr)
+	return srv.ListenAndServe()
+}
+
+/* ----------------------------------------------------------------------
+   9️⃣  TEMPLATE HOT RELOAD
+   ---------------------------------------------------------------------- */
+const (
+	templatesDir = "templates"
+	importedDir  = "./imported"
+)
+
+// reloadPageTemplate reparses one named page-template file and swaps the
+// corresponding package variable in under pageTmplMu.
+func reloadPageTemplate(name string) error {
+	t, err := template.ParseFiles(filepath.Join(templatesDir, name))
+	if err != nil {
+		return err
+	}
+	pageTmplMu.Lock()
+	defer pageTmplMu.Unlock()
+	switch name {
+	case "login.html":
+		loginTmpl = t
+	case "register.html":
+		registerTmpl = t
+	case "app.html":
+		appTmpl = t
+	case "forgotpw.html":
+		forgotPwTmpl = t
+	case "resetpw.html":
+		resetPwTmpl = t
+	case "404.html":
+		notFoundTmpl = t
+	}
+	return nil
+}
+
+// reloadAllTemplates rescans every *.html file in templatesDir.
+func reloadAllTemplates() {
+	entries, err := os.ReadDir(templatesDir)
+	if err != nil {
+		log.Printf("templates: cannot scan %s: %v", templatesDir, err)
+		return
+	}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".html") {
+			continue
+		}
+		if err := reloadPageTemplate(e.Name()); err != nil {
+			log.Printf("templates: reload %s: %v", e.Name(), err)
+		} else {
+			log.Printf("templates: reloaded %s", e.Name())
+		}
+	}
+}
+
+// loadImportedTemplate reads one uploaded file from disk into db's
+// templates, wrapping it the same way importHandler does.
+func loadImportedTemplate(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	name := filepath.Base(path)
+	wrapped := fmt.Sprintf("<template data-name=\"%s\">\n%s\n</template>", name, string(content))
+	return db.PutTemplate(name, wrapped)
+}
+
+// loadImportedTemplates rescans importedDir so uploads made via /import
+// survive a restart.
+func loadImportedTemplates() {
+	entries, err := os.ReadDir(importedDir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("imported: cannot scan %s: %v", importedDir, err)
+		}
+		return
+	}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		path := filepath.Join(importedDir, e.Name())
+		if err := loadImportedTemplate(path); err != nil {
+			log.Printf("imported: load %s: %v", e.Name(), err)
+		}
+	}
+}
+
+// templateWatcher watches templatesDir and importedDir for changes and
+// keeps the page templates and db's imported templates in sync for as
+// long as the process runs.
+func templateWatcher() {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.Printf("templates: fsnotify unavailable: %v", err)
+		return
+	}
+	defer watcher.Close()
+
+	if err := watcher.Add(templatesDir); err != nil {
+		log.Printf("templates: cannot watch %s: %v", templatesDir, err)
+	}
+	if err := os.MkdirAll(importedDir, 0755); err != nil {
+		log.Printf("imported: cannot create %s: %v", importedDir, err)
+	} else if err := watcher.Add(importedDir); err != nil {
+		log.Printf("templates: cannot watch %s: %v", importedDir, err)
+	}
+
+	for {
+		select {
+		case ev, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if ev.Op&(fsnotify.Write|fsnotify.Chmod|fsnotify.Create) == 0 {
 				continue
 			}
-			valueToFiles[v] = append(valueToFiles[v], baseName)
+			// fsnotify reports paths under the cleaned watch path
+			// ("imported/x"), so compare against the cleaned importedDir.
+			if filepath.Dir(ev.Name) == filepath.Clean(importedDir) {
+				if err := loadImportedTemplate(ev.Name); err != nil {
+					log.Printf("imported: reload %s: %v", ev.Name, err)
+				} else {
+					log.Printf("imported: reloaded %s", ev.Name)
+				}
+				continue
+			}
+			if !strings.HasSuffix(ev.Name, ".html") {
+				continue
+			}
+			name := filepath.Base(ev.Name)
+			if err := reloadPageTemplate(name); err != nil {
+				log.Printf("templates: reload %s: %v", name, err)
+			} else {
+				log.Printf("templates: reloaded %s", name)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("templates: watcher error: %v", err)
 		}
 	}
 }
 
+// ----- Admin: force a full template rescan -----------------------------
+func reloadTemplatesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	reloadAllTemplates()
+	loadImportedTemplates()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "reloaded"})
+}
+
 /* ----------------------------------------------------------------------
-   7️⃣  SERVER STARTUP
+   🔟  WEBSOCKET PUSH BROKER
+   ---------------------------------------------------------------------- */
+
+// Event is the JSON message pushed to subscribed websocket clients
+// whenever queryHandler stores a new form.
+type Event struct {
+	Type   string   `json:"type"`
+	Base   string   `json:"base,omitempty"`
+	Keys   []string `json:"keys,omitempty"`
+	Values []string `json:"values,omitempty"`
+}
+
+// Filter narrows which Events a subscriber receives. A zero Filter
+// matches every Event; a non-empty Key/Value only matches Events whose
+// Keys/Values contain it.
+type Filter struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+func containsStr(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Matches reports whether ev satisfies f.
+func (f Filter) Matches(ev Event) bool {
+	if f.Key != "" && !containsStr(ev.Keys, f.Key) {
+		return false
+	}
+	if f.Value != "" && !containsStr(ev.Values, f.Value) {
+		return false
+	}
+	return true
+}
+
+func keysOf(values map[string][]string) []string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+func valuesOf(values map[string][]string) []string {
+	var out []string
+	for _, vs := range values {
+		out = append(out, vs...)
+	}
+	return out
+}
+
+// subscription pairs a subscriber's event channel with its current
+// Filter; the filter is replaced wholesale whenever the client sends a
+// new "subscribe" frame.
+type subscription struct {
+	ch     chan Event
+	filter Filter
+}
+
+// broker fans Events out to every matching subscriber. Subscribe,
+// Unsubscribe, and Publish are all safe for concurrent use.
+type broker struct {
+	mu   sync.RWMutex
+	subs []*subscription
+}
+
+var formBroker = &broker{}
+
+// Subscribe registers ch to receive Events matching filter. The caller
+// owns ch and must Unsubscribe it when done.
+func (b *broker) Subscribe(ch chan Event, filter Filter) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subs = append(b.subs, &subscription{ch: ch, filter: filter})
+}
+
+// Unsubscribe removes ch from the broker. It is a no-op if ch was never
+// subscribed (or was already removed).
+func (b *broker) Unsubscribe(ch chan Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i, s := range b.subs {
+		if s.ch == ch {
+			b.subs = append(b.subs[:i], b.subs[i+1:]...)
+			return
+		}
+	}
+}
+
+// SetFilter replaces the Filter for the subscription holding ch, in
+// response to a client's "subscribe" frame.
+func (b *broker) SetFilter(ch chan Event, filter Filter) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, s := range b.subs {
+		if s.ch == ch {
+			s.filter = filter
+			return
+		}
+	}
+}
+
+// Publish delivers ev to every subscriber whose filter matches. A
+// subscriber whose buffer is full is skipped for this event rather than
+// blocking the publisher, and the drop is logged.
+func (b *broker) Publish(ev Event) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, s := range b.subs {
+		if !s.filter.Matches(ev) {
+			continue
+		}
+		select {
+		case s.ch <- ev:
+		default:
+			log.Printf("ws: dropping %s event for slow subscriber", ev.Type)
+		}
+	}
+}
+
+const (
+	wsSubscriberBuffer = 16
+	wsPingPeriod       = 30 * time.Second
+	wsPongWait         = 2 * wsPingPeriod // matches Galene: close after two missed pings
+	wsWriteWait        = 10 * time.Second
+)
+
+var wsUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+// wsHandler upgrades an authenticated connection and streams Events the
+// client has subscribed to via the formBroker.
+func wsHandler(w http.ResponseWriter, r *http.Request) {
+	username := getUsername(r)
+	if username == "" {
+		http.Error(w, "unauthorized – please log in", http.StatusUnauthorized)
+		return
+	}
+
+	conn, err := wsUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Printf("ws: upgrade failed: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	ch := make(chan Event, wsSubscriberBuffer)
+	formBroker.Subscribe(ch, Filter{})
+	defer formBroker.Unsubscribe(ch)
+
+	done := make(chan struct{})
+	go wsReadLoop(conn, ch, done)
+	wsWriteLoop(conn, ch, done)
+}
+
+// wsReadLoop reads subscribe frames and pong control messages until the
+// connection errors or is closed by wsWriteLoop.
+func wsReadLoop(conn *websocket.Conn, ch chan Event, done chan struct{}) {
+	defer close(done)
+	conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	conn.SetPongHandler(func(string) error {
+		conn.SetReadDeadline(time.Now().Add(wsPongWait))
+		return nil
+	})
+	for {
+		var frame struct {
+			Subscribe *Filter `json:"subscribe"`
+		}
+		if err := conn.ReadJSON(&frame); err != nil {
+			return
+		}
+		if frame.Subscribe != nil {
+			formBroker.SetFilter(ch, *frame.Subscribe)
+		}
+	}
+}
+
+// wsWriteLoop pushes Events as they arrive and pings every wsPingPeriod
+// to keep the connection alive and detect dead peers.
+func wsWriteLoop(conn *websocket.Conn, ch chan Event, done chan struct{}) {
+	ticker := time.NewTicker(wsPingPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case ev := <-ch:
+			conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := conn.WriteJSON(ev); err != nil {
+				return
+			}
+		case <-ticker.C:
+			conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		case <-done:
+			return
+		}
+	}
+}
+
+/* ----------------------------------------------------------------------
+   1️⃣1️⃣  SERVER STARTUP
    ---------------------------------------------------------------------- */
 func main() {
+	// -------------------------------------------------------------
+	// Open the persistent store
+	// -------------------------------------------------------------
+	dbPath := os.Getenv("POD_DB_PATH")
+	if dbPath == "" {
+		dbPath = "./data/pod.db"
+	}
+	if err := os.MkdirAll(filepath.Dir(dbPath), 0755); err != nil {
+		log.Fatalf("failed to create db directory: %v", err)
+	}
+	sqliteStore, err := sqlite.Open(dbPath)
+	if err != nil {
+		log.Fatalf("failed to open store at %s: %v", dbPath, err)
+	}
+	defer sqliteStore.Close()
+	db = sqliteStore
+
 	// -------------------------------------------------------------
 	// Load HTML templates from the ./templates directory
 	// -------------------------------------------------------------
 	if err := loadTemplates(); err != nil {
 		log.Fatalf("failed to load templates: %v", err)
 	}
+	loadImportedTemplates()
+	go templateWatcher()
 
 	// -------------------------------------------------------------
-	// Route registration
+	// Route registration (every route runs through mungeHandler)
 	// -------------------------------------------------------------
-	http.HandleFunc("/", rootRedirect)
-	http.HandleFunc("/login", loginHandler)
-	http.HandleFunc("/register", registerHandler)
-	http.HandleFunc("/logout", logoutHandler)
-	http.HandleFunc("/app", appHandler)
+	http.HandleFunc("/", mungeHandler(rootRedirect))
+	http.HandleFunc("/login", mungeHandler(loginHandler))
+	http.HandleFunc("/register", mungeHandler(registerHandler))
+	http.HandleFunc("/logout", mungeHandler(logoutHandler))
+	http.HandleFunc("/app", mungeHandler(appHandler))
+	http.HandleFunc("/passwordReset", mungeHandler(passwordResetRequestHandler))
+	http.HandleFunc("/resetPassword", mungeHandler(passwordResetHandler))
 
 	// API endpoints
-	http.HandleFunc("/api/saveContext", saveContextHandler)
-	http.HandleFunc("/api/query", queryHandler)
+	http.HandleFunc("/api/saveContext", mungeHandler(saveContextHandler))
+	http.HandleFunc("/api/query", mungeHandler(queryHandler))
 
 	// Import HTML form (multipart upload)
-	http.HandleFunc("/import", importHandler)
+	http.HandleFunc("/import", mungeHandler(importHandler))
+
+	// Admin: force a full template rescan
+	http.HandleFunc("/api/reloadTemplates", mungeHandler(reloadTemplatesHandler))
+
+	// Live push of newly stored forms
+	http.HandleFunc("/ws", mungeHandler(wsHandler))
 
 	// Template serving endpoints
-	http.HandleFunc("/templates/manifest.json", templatesManifestHandler)
+	http.HandleFunc("/templates/manifest.json", mungeHandler(templatesManifestHandler))
 	// Anything under /templates/ (except manifest) serves a single template file
-	http.HandleFunc("/templates/", templateFileHandler)
+	http.HandleFunc("/templates/", mungeHandler(templateFileHandler))
 
 	// -------------------------------------------------------------
-	// Start the HTTP server
+	// Start the HTTP(S) server
 	// -------------------------------------------------------------
-	port := "8080"
-	log.Printf("🚀 Server listening on http://localhost:%s/app", port)
-	if err := http.ListenAndServe(":"+port, nil); err != nil {
+	addr := os.Getenv("POD_ADDR")
+	if addr == "" {
+		addr = ":8080"
+	}
+	if err := startServer(addr, os.Getenv("POD_TLS_CERT"), os.Getenv("POD_TLS_KEY")); err != nil {
 		log.Fatalf("server error: %v", err)
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"crypto/tls"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/AzzurroTech/POD/store"
+	"github.com/AzzurroTech/POD/store/memory"
+)
+
+// TestVerifyPassword covers the hash shapes VerifyPassword has to accept:
+// a current bcrypt hash, a legacy SHA-256(salt‖password) hash, and a
+// corrupted bcrypt-prefixed hash. A bcrypt hash created under a
+// since-changed POD_BCRYPT_COST is covered by
+// TestVerifyPasswordCostChangeNoRehash.
+func TestVerifyPassword(t *testing.T) {
+	const password = "correct horse battery staple"
+
+	salt := genSalt()
+	legacyHash := hashPassword(salt, password)
+
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("bcrypt.GenerateFromPassword: %v", err)
+	}
+
+	corrupted := append([]byte(bcryptPrefix), []byte("not-a-real-bcrypt-hash")...)
+
+	cases := []struct {
+		name             string
+		rec              *store.User
+		password         string
+		wantOK           bool
+		wantNeedsUpgrade bool
+	}{
+		{
+			name:             "bcrypt match",
+			rec:              &store.User{PassHash: append([]byte(bcryptPrefix), bcryptHash...)},
+			password:         password,
+			wantOK:           true,
+			wantNeedsUpgrade: false,
+		},
+		{
+			name:             "bcrypt mismatch",
+			rec:              &store.User{PassHash: append([]byte(bcryptPrefix), bcryptHash...)},
+			password:         "wrong password",
+			wantOK:           false,
+			wantNeedsUpgrade: false,
+		},
+		{
+			name:             "legacy sha256 match triggers upgrade",
+			rec:              &store.User{Salt: salt, PassHash: legacyHash},
+			password:         password,
+			wantOK:           true,
+			wantNeedsUpgrade: true,
+		},
+		{
+			name:             "legacy sha256 mismatch",
+			rec:              &store.User{Salt: salt, PassHash: legacyHash},
+			password:         "wrong password",
+			wantOK:           false,
+			wantNeedsUpgrade: true,
+		},
+		{
+			name:             "corrupted bcrypt prefix fails closed",
+			rec:              &store.User{PassHash: corrupted},
+			password:         password,
+			wantOK:           false,
+			wantNeedsUpgrade: false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ok, needsUpgrade := VerifyPassword(tc.rec, tc.password)
+			if ok != tc.wantOK || needsUpgrade != tc.wantNeedsUpgrade {
+				t.Errorf("VerifyPassword() = (%v, %v), want (%v, %v)", ok, needsUpgrade, tc.wantOK, tc.wantNeedsUpgrade)
+			}
+		})
+	}
+}
+
+// TestVerifyPasswordCostChangeNoRehash checks that a bcrypt hash created
+// under one POD_BCRYPT_COST still verifies, without being flagged for a
+// rehash, after the env var changes the cost used for *new* hashes —
+// bcrypt embeds its own cost in the hash, so VerifyPassword must not
+// depend on the current bcryptCost().
+func TestVerifyPasswordCostChangeNoRehash(t *testing.T) {
+	const password = "correct horse battery staple"
+
+	old, hadOld := os.LookupEnv("POD_BCRYPT_COST")
+	defer func() {
+		if hadOld {
+			os.Setenv("POD_BCRYPT_COST", old)
+		} else {
+			os.Unsetenv("POD_BCRYPT_COST")
+		}
+	}()
+
+	os.Setenv("POD_BCRYPT_COST", "4")
+	passHash, err := hashPasswordBcrypt(password)
+	if err != nil {
+		t.Fatalf("hashPasswordBcrypt at cost 4: %v", err)
+	}
+
+	os.Setenv("POD_BCRYPT_COST", "6")
+	rec := &store.User{PassHash: passHash}
+	ok, needsUpgrade := VerifyPassword(rec, password)
+	if !ok || needsUpgrade {
+		t.Errorf("VerifyPassword() = (%v, %v), want (true, false) after cost change", ok, needsUpgrade)
+	}
+}
+
+// fakeEmailSender is the substitute EmailSender the reset flow was built
+// to accept; this test is what exercises that seam.
+type fakeEmailSender struct {
+	calls []struct{ to, subject, body string }
+}
+
+func (f *fakeEmailSender) Send(to, subject, body string) error {
+	f.calls = append(f.calls, struct{ to, subject, body string }{to, subject, body})
+	return nil
+}
+
+// TestPasswordResetRequestHandlerSendsEmail drives passwordResetRequestHandler
+// end to end against a fake EmailSender and an in-memory store, asserting a
+// reset email is actually sent for a known user.
+func TestPasswordResetRequestHandlerSendsEmail(t *testing.T) {
+	origForgotPwTmpl := forgotPwTmpl
+	forgotPwTmpl = template.Must(template.New("forgotpw").Parse("{{.Error}}|{{.Sent}}"))
+	defer func() { forgotPwTmpl = origForgotPwTmpl }()
+
+	tmpDir := t.TempDir()
+	origPwresetFile, origPwresetWaitFile := pwresetFile, pwresetWaitFile
+	pwresetFile = filepath.Join(tmpDir, "pwreset.tsv")
+	pwresetWaitFile = filepath.Join(tmpDir, "pwreset_wait.tsv")
+	defer func() { pwresetFile, pwresetWaitFile = origPwresetFile, origPwresetWaitFile }()
+
+	origDB := db
+	mem := memory.New()
+	if err := mem.CreateUser("alice", genSalt(), []byte("irrelevant-hash")); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	db = mem
+	defer func() { db = origDB }()
+
+	origSender := emailSender
+	fake := &fakeEmailSender{}
+	emailSender = fake
+	defer func() { emailSender = origSender }()
+
+	req := httptest.NewRequest(http.MethodPost, "/passwordReset", strings.NewReader("username=alice"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr := httptest.NewRecorder()
+
+	passwordResetRequestHandler(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rr.Code, http.StatusOK)
+	}
+	if len(fake.calls) != 1 {
+		t.Fatalf("emailSender.Send called %d times, want 1", len(fake.calls))
+	}
+	if fake.calls[0].to != "alice" {
+		t.Errorf("Send(to=%q), want %q", fake.calls[0].to, "alice")
+	}
+	if !strings.Contains(fake.calls[0].body, "/resetPassword?token=") {
+		t.Errorf("Send body = %q, want a reset link", fake.calls[0].body)
+	}
+}
+
+func TestRequestScheme(t *testing.T) {
+	cases := []struct {
+		name  string
+		setup func(r *http.Request)
+		want  string
+	}{
+		{
+			name:  "plain http",
+			setup: func(r *http.Request) {},
+			want:  "http",
+		},
+		{
+			name:  "direct TLS",
+			setup: func(r *http.Request) { r.TLS = &tls.ConnectionState{} },
+			want:  "https",
+		},
+		{
+			name:  "behind a TLS-terminating proxy",
+			setup: func(r *http.Request) { r.Header.Set("X-Forwarded-Proto", "https") },
+			want:  "https",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/passwordReset", nil)
+			tc.setup(req)
+			if got := requestScheme(req); got != tc.want {
+				t.Errorf("requestScheme() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPruneExpiredTSV(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "reset.tsv")
+	now := time.Now()
+	fresh := now.UnixNano()
+	stale := now.Add(-2 * resetTokenTTL).UnixNano()
+
+	if err := appendTSV(path, "fresh-token", "alice", strconv.FormatInt(fresh, 10)); err != nil {
+		t.Fatalf("appendTSV: %v", err)
+	}
+	if err := appendTSV(path, "stale-token", "bob", strconv.FormatInt(stale, 10)); err != nil {
+		t.Fatalf("appendTSV: %v", err)
+	}
+
+	if err := pruneExpiredTSV(path, resetTokenTTL, 2); err != nil {
+		t.Fatalf("pruneExpiredTSV: %v", err)
+	}
+
+	rows, err := readTSVLines(path)
+	if err != nil {
+		t.Fatalf("readTSVLines: %v", err)
+	}
+	if len(rows) != 1 || rows[0][0] != "fresh-token" {
+		t.Errorf("readTSVLines() after prune = %v, want only the fresh-token row", rows)
+	}
+}
@@ -258,6 +258,73 @@ func handler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// -------------------------------------------------------------------
+// Security middleware: CSP headers + a custom 404 page, TLS startup
+// -------------------------------------------------------------------
+const cspHeader = "default-src 'self'; img-src data: 'self'; connect-src ws: wss: 'self'"
+
+var notFoundTmpl *template.Template // optional; nil if templates/404.html isn't present
+
+// mungeResponseWriter lets mungeHandler intercept a 404 response and
+// substitute our own rendered not-found page for the handler's body.
+type mungeResponseWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+	suppress    bool
+}
+
+func (m *mungeResponseWriter) WriteHeader(status int) {
+	if m.wroteHeader {
+		return
+	}
+	m.wroteHeader = true
+	if status == http.StatusNotFound {
+		m.suppress = true
+	}
+	m.ResponseWriter.WriteHeader(status)
+}
+
+func (m *mungeResponseWriter) Write(b []byte) (int, error) {
+	if !m.wroteHeader {
+		m.WriteHeader(http.StatusOK)
+	}
+	if m.suppress {
+		return len(b), nil
+	}
+	return m.ResponseWriter.Write(b)
+}
+
+func mungeHandler(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		h := w.Header()
+		h.Set("Content-Security-Policy", cspHeader)
+		h.Set("X-Content-Type-Options", "nosniff")
+		h.Set("Referrer-Policy", "same-origin")
+
+		mw := &mungeResponseWriter{ResponseWriter: w}
+		next(mw, r)
+		if mw.suppress && notFoundTmpl != nil {
+			notFoundTmpl.Execute(w, nil)
+		}
+	}
+}
+
+// startServer runs the HTTP server on addr, upgrading to TLS when both
+// tlsCert and tlsKey are non-empty.
+func startServer(addr, tlsCert, tlsKey string) error {
+	srv := &http.Server{
+		Addr:              addr,
+		IdleTimeout:       120 * time.Second,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	if tlsCert != "" && tlsKey != "" {
+		fmt.Printf("Server listening on https://%s/\n", addr)
+		return srv.ListenAndServeTLS(tlsCert, tlsKey)
+	}
+	fmt.Printf("Server listening on http://%s/\n", addr)
+	return srv.ListenAndServe()
+}
+
 // -------------------------------------------------------------------
 // Main entry point
 // -------------------------------------------------------------------
@@ -267,11 +334,17 @@ func main() {
 		panic(fmt.Sprintf("cannot create storage folder: %v", err))
 	}
 
-	http.HandleFunc("/", handler)
+	if t, err := template.ParseFiles(filepath.Join("templates", "404.html")); err == nil {
+		notFoundTmpl = t
+	}
+
+	http.HandleFunc("/", mungeHandler(handler))
 
-	port := "8080"
-	fmt.Printf("Server listening on http://localhost:%s/\n", port)
-	if err := http.ListenAndServe(":"+port, nil); err != nil {
+	addr := os.Getenv("POD_ADDR")
+	if addr == "" {
+		addr = ":8080"
+	}
+	if err := startServer(addr, os.Getenv("POD_TLS_CERT"), os.Getenv("POD_TLS_KEY")); err != nil {
 		panic(err)
 	}
 }
\ No newline at end of file
@@ -0,0 +1,217 @@
+// Package memory is an in-memory store.Store, used for tests; pod's main()
+// always opens the sqlite store.
+package memory
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AzzurroTech/POD/store"
+)
+
+// Store keeps every table as a plain map guarded by a mutex, mirroring
+// the maps pod used before the sqlite-backed store was introduced.
+type Store struct {
+	mu          sync.RWMutex
+	users       map[string]*store.User
+	sessions    map[string]string
+	nextSessNum int64
+
+	keyToFiles   map[string][]string
+	valueToFiles map[string][]string
+	storedFiles  []string
+
+	tmplMu    sync.RWMutex
+	templates map[string]string
+}
+
+// New returns an empty in-memory Store.
+func New() *Store {
+	return &Store{
+		users:        make(map[string]*store.User),
+		sessions:     make(map[string]string),
+		nextSessNum:  1,
+		keyToFiles:   make(map[string][]string),
+		valueToFiles: make(map[string][]string),
+		templates:    make(map[string]string),
+	}
+}
+
+func (s *Store) CreateUser(username string, salt, passHash []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.users[username]; exists {
+		return store.ErrUserExists
+	}
+	s.users[username] = &store.User{Username: username, Salt: salt, PassHash: passHash}
+	return nil
+}
+
+func (s *Store) GetUser(username string) (*store.User, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	u, ok := s.users[username]
+	if !ok {
+		return nil, false, nil
+	}
+	cp := *u
+	return &cp, true, nil
+}
+
+func (s *Store) UpdateUserContext(username, encCtxB64 string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if u, ok := s.users[username]; ok {
+		u.EncContextB64 = encCtxB64
+	}
+	return nil
+}
+
+func (s *Store) UpdateUserPassword(username string, salt, passHash []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if u, ok := s.users[username]; ok {
+		u.Salt = salt
+		u.PassHash = passHash
+	}
+	return nil
+}
+
+func (s *Store) NewSession(username string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := fmt.Sprintf("%d-%d", time.Now().UnixNano(), s.nextSessNum)
+	s.nextSessNum++
+	s.sessions[id] = username
+	return id, nil
+}
+
+func (s *Store) LookupSession(id string) (string, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	username, ok := s.sessions[id]
+	return username, ok, nil
+}
+
+func (s *Store) DeleteSession(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, id)
+	return nil
+}
+
+func (s *Store) DeleteSessionsForUser(username string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, u := range s.sessions {
+		if u == username {
+			delete(s.sessions, id)
+		}
+	}
+	return nil
+}
+
+func (s *Store) InsertForm(base string, kv map[string][]string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.storedFiles = append(s.storedFiles, base)
+	for k, vals := range kv {
+		k = strings.TrimSpace(k)
+		if k == "" {
+			continue
+		}
+		s.keyToFiles[k] = append(s.keyToFiles[k], base)
+		for _, v := range vals {
+			v = strings.TrimSpace(v)
+			if v == "" {
+				continue
+			}
+			s.valueToFiles[v] = append(s.valueToFiles[v], base)
+		}
+	}
+	return nil
+}
+
+func (s *Store) QueryForms(query map[string][]string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if len(query) == 0 {
+		cpy := make([]string, len(s.storedFiles))
+		copy(cpy, s.storedFiles)
+		return cpy, nil
+	}
+
+	var candidateSet map[string]struct{}
+	first := true
+	for qk, qvs := range query {
+		tmp := make(map[string]struct{})
+		for _, fn := range s.keyToFiles[qk] {
+			tmp[fn] = struct{}{}
+		}
+		if len(qvs) > 0 {
+			filtered := make(map[string]struct{})
+			for _, val := range qvs {
+				for _, fn := range s.valueToFiles[val] {
+					if _, ok := tmp[fn]; ok {
+						filtered[fn] = struct{}{}
+					}
+				}
+			}
+			tmp = filtered
+		}
+		if first {
+			candidateSet = tmp
+			first = false
+		} else {
+			newSet := make(map[string]struct{})
+			for fn := range candidateSet {
+				if _, ok := tmp[fn]; ok {
+					newSet[fn] = struct{}{}
+				}
+			}
+			candidateSet = newSet
+		}
+		if len(candidateSet) == 0 {
+			break
+		}
+	}
+
+	var result []string
+	for _, fn := range s.storedFiles {
+		if _, ok := candidateSet[fn]; ok {
+			result = append(result, fn)
+		}
+	}
+	return result, nil
+}
+
+func (s *Store) PutTemplate(name, html string) error {
+	s.tmplMu.Lock()
+	defer s.tmplMu.Unlock()
+	s.templates[name] = html
+	return nil
+}
+
+func (s *Store) GetTemplate(name string) (string, bool, error) {
+	s.tmplMu.RLock()
+	defer s.tmplMu.RUnlock()
+	html, ok := s.templates[name]
+	return html, ok, nil
+}
+
+func (s *Store) ListTemplates() ([]string, error) {
+	s.tmplMu.RLock()
+	defer s.tmplMu.RUnlock()
+	names := make([]string, 0, len(s.templates))
+	for n := range s.templates {
+		names = append(names, n)
+	}
+	return names, nil
+}
+
+func (s *Store) Close() error { return nil }
+
+var _ store.Store = (*Store)(nil)
@@ -0,0 +1,139 @@
+package memory
+
+import (
+	"errors"
+	"sort"
+	"testing"
+
+	"github.com/AzzurroTech/POD/store"
+)
+
+func TestStoreUsers(t *testing.T) {
+	s := New()
+
+	if err := s.CreateUser("alice", []byte("salt"), []byte("hash")); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	if err := s.CreateUser("alice", []byte("salt2"), []byte("hash2")); !errors.Is(err, store.ErrUserExists) {
+		t.Fatalf("CreateUser duplicate = %v, want store.ErrUserExists", err)
+	}
+
+	u, ok, err := s.GetUser("alice")
+	if err != nil || !ok {
+		t.Fatalf("GetUser(alice) = (%v, %v, %v)", u, ok, err)
+	}
+	if string(u.PassHash) != "hash" {
+		t.Errorf("PassHash = %q, want %q", u.PassHash, "hash")
+	}
+
+	if _, ok, err := s.GetUser("nobody"); err != nil || ok {
+		t.Fatalf("GetUser(nobody) = (_, %v, %v), want (_, false, nil)", ok, err)
+	}
+
+	if err := s.UpdateUserContext("alice", "enc-ctx"); err != nil {
+		t.Fatalf("UpdateUserContext: %v", err)
+	}
+	if err := s.UpdateUserPassword("alice", []byte("newsalt"), []byte("newhash")); err != nil {
+		t.Fatalf("UpdateUserPassword: %v", err)
+	}
+	u, _, _ = s.GetUser("alice")
+	if u.EncContextB64 != "enc-ctx" || string(u.PassHash) != "newhash" {
+		t.Errorf("GetUser(alice) after updates = %+v", u)
+	}
+}
+
+func TestStoreSessions(t *testing.T) {
+	s := New()
+
+	id, err := s.NewSession("alice")
+	if err != nil {
+		t.Fatalf("NewSession: %v", err)
+	}
+	if username, ok, err := s.LookupSession(id); err != nil || !ok || username != "alice" {
+		t.Fatalf("LookupSession(%q) = (%q, %v, %v), want (alice, true, nil)", id, username, ok, err)
+	}
+
+	other, err := s.NewSession("alice")
+	if err != nil {
+		t.Fatalf("NewSession: %v", err)
+	}
+	if err := s.DeleteSessionsForUser("alice"); err != nil {
+		t.Fatalf("DeleteSessionsForUser: %v", err)
+	}
+	for _, sid := range []string{id, other} {
+		if _, ok, _ := s.LookupSession(sid); ok {
+			t.Errorf("LookupSession(%q) still valid after DeleteSessionsForUser", sid)
+		}
+	}
+
+	id2, _ := s.NewSession("bob")
+	if err := s.DeleteSession(id2); err != nil {
+		t.Fatalf("DeleteSession: %v", err)
+	}
+	if _, ok, _ := s.LookupSession(id2); ok {
+		t.Errorf("LookupSession(%q) still valid after DeleteSession", id2)
+	}
+}
+
+func TestStoreQueryForms(t *testing.T) {
+	s := New()
+
+	if err := s.InsertForm("form_1", map[string][]string{"color": {"red"}, "size": {"m"}}); err != nil {
+		t.Fatalf("InsertForm: %v", err)
+	}
+	if err := s.InsertForm("form_2", map[string][]string{"color": {"blue"}, "size": {"m"}}); err != nil {
+		t.Fatalf("InsertForm: %v", err)
+	}
+
+	all, err := s.QueryForms(nil)
+	if err != nil {
+		t.Fatalf("QueryForms(nil): %v", err)
+	}
+	if len(all) != 2 {
+		t.Fatalf("QueryForms(nil) = %v, want 2 entries", all)
+	}
+
+	matches, err := s.QueryForms(map[string][]string{"color": {"red"}})
+	if err != nil {
+		t.Fatalf("QueryForms(color=red): %v", err)
+	}
+	if len(matches) != 1 || matches[0] != "form_1" {
+		t.Errorf("QueryForms(color=red) = %v, want [form_1]", matches)
+	}
+
+	matches, err = s.QueryForms(map[string][]string{"size": {"m"}, "color": {"blue"}})
+	if err != nil {
+		t.Fatalf("QueryForms(size=m,color=blue): %v", err)
+	}
+	if len(matches) != 1 || matches[0] != "form_2" {
+		t.Errorf("QueryForms(size=m,color=blue) = %v, want [form_2]", matches)
+	}
+}
+
+func TestStoreTemplates(t *testing.T) {
+	s := New()
+
+	if err := s.PutTemplate("a.html", "<p>a</p>"); err != nil {
+		t.Fatalf("PutTemplate: %v", err)
+	}
+	if err := s.PutTemplate("b.html", "<p>b</p>"); err != nil {
+		t.Fatalf("PutTemplate: %v", err)
+	}
+
+	html, ok, err := s.GetTemplate("a.html")
+	if err != nil || !ok || html != "<p>a</p>" {
+		t.Fatalf("GetTemplate(a.html) = (%q, %v, %v)", html, ok, err)
+	}
+	if _, ok, err := s.GetTemplate("missing.html"); err != nil || ok {
+		t.Fatalf("GetTemplate(missing.html) = (_, %v, %v), want (_, false, nil)", ok, err)
+	}
+
+	names, err := s.ListTemplates()
+	if err != nil {
+		t.Fatalf("ListTemplates: %v", err)
+	}
+	sort.Strings(names)
+	if len(names) != 2 || names[0] != "a.html" || names[1] != "b.html" {
+		t.Errorf("ListTemplates() = %v, want [a.html b.html]", names)
+	}
+}
@@ -0,0 +1,231 @@
+// Package sqlite is the persistent store.Store backend, using the
+// pure-Go modernc.org/sqlite driver so pod needs no cgo toolchain.
+package sqlite
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/AzzurroTech/POD/store"
+)
+
+//go:embed schema.sql
+var schemaFS embed.FS
+
+// Store is a store.Store backed by a single SQLite database file.
+type Store struct {
+	db *sql.DB
+}
+
+// Open opens (creating if necessary) the database at path and runs the
+// schema migration.
+func Open(path string) (*Store, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	// modernc.org/sqlite serializes writes internally; cap the pool so we
+	// don't hand out concurrent connections the driver would just queue.
+	db.SetMaxOpenConns(1)
+
+	schema, err := schemaFS.ReadFile("schema.sql")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(string(schema)); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("running schema migration: %w", err)
+	}
+	return &Store{db: db}, nil
+}
+
+func (s *Store) Close() error { return s.db.Close() }
+
+func (s *Store) CreateUser(username string, salt, passHash []byte) error {
+	_, err := s.db.Exec(`INSERT INTO users(username, salt, pass_hash) VALUES (?, ?, ?)`,
+		username, salt, passHash)
+	if err != nil && strings.Contains(err.Error(), "UNIQUE") {
+		return store.ErrUserExists
+	}
+	return err
+}
+
+func (s *Store) GetUser(username string) (*store.User, bool, error) {
+	row := s.db.QueryRow(`SELECT username, salt, pass_hash, enc_ctx FROM users WHERE username = ?`, username)
+	var u store.User
+	if err := row.Scan(&u.Username, &u.Salt, &u.PassHash, &u.EncContextB64); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	return &u, true, nil
+}
+
+func (s *Store) UpdateUserContext(username, encCtxB64 string) error {
+	_, err := s.db.Exec(`UPDATE users SET enc_ctx = ? WHERE username = ?`, encCtxB64, username)
+	return err
+}
+
+func (s *Store) UpdateUserPassword(username string, salt, passHash []byte) error {
+	_, err := s.db.Exec(`UPDATE users SET salt = ?, pass_hash = ? WHERE username = ?`, salt, passHash, username)
+	return err
+}
+
+func (s *Store) NewSession(username string) (string, error) {
+	id, err := newSessionID()
+	if err != nil {
+		return "", err
+	}
+	_, err = s.db.Exec(`INSERT INTO sessions(id, username) VALUES (?, ?)`, id, username)
+	return id, err
+}
+
+// newSessionID generates an unguessable session id, the same way
+// generateResetToken does for password-reset tokens.
+func newSessionID() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func (s *Store) LookupSession(id string) (string, bool, error) {
+	row := s.db.QueryRow(`SELECT username FROM sessions WHERE id = ?`, id)
+	var username string
+	if err := row.Scan(&username); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", false, nil
+		}
+		return "", false, err
+	}
+	return username, true, nil
+}
+
+func (s *Store) DeleteSession(id string) error {
+	_, err := s.db.Exec(`DELETE FROM sessions WHERE id = ?`, id)
+	return err
+}
+
+func (s *Store) DeleteSessionsForUser(username string) error {
+	_, err := s.db.Exec(`DELETE FROM sessions WHERE username = ?`, username)
+	return err
+}
+
+func (s *Store) InsertForm(base string, kv map[string][]string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`INSERT INTO forms(base, created_at) VALUES (?, ?)`, base, time.Now().UnixNano()); err != nil {
+		tx.Rollback()
+		return err
+	}
+	for k, vals := range kv {
+		k = strings.TrimSpace(k)
+		if k == "" {
+			continue
+		}
+		for _, v := range vals {
+			v = strings.TrimSpace(v)
+			if v == "" {
+				continue
+			}
+			if _, err := tx.Exec(`INSERT INTO form_kv(base, key, value) VALUES (?, ?, ?)`, base, k, v); err != nil {
+				tx.Rollback()
+				return err
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+// QueryForms intersects form_kv on every requested (key, value) pair in a
+// single query instead of pod's old in-memory map walk.
+func (s *Store) QueryForms(query map[string][]string) ([]string, error) {
+	if len(query) == 0 {
+		return s.queryRows(`SELECT base FROM forms ORDER BY created_at`)
+	}
+
+	var clauses []string
+	var args []interface{}
+	for k, vals := range query {
+		if len(vals) == 0 {
+			clauses = append(clauses, `base IN (SELECT base FROM form_kv WHERE key = ?)`)
+			args = append(args, k)
+			continue
+		}
+		placeholders := strings.TrimSuffix(strings.Repeat("?,", len(vals)), ",")
+		clauses = append(clauses, fmt.Sprintf(`base IN (SELECT base FROM form_kv WHERE key = ? AND value IN (%s))`, placeholders))
+		args = append(args, k)
+		for _, v := range vals {
+			args = append(args, v)
+		}
+	}
+	q := fmt.Sprintf(`SELECT base FROM forms WHERE %s ORDER BY created_at`, strings.Join(clauses, " AND "))
+	return s.queryRows(q, args...)
+}
+
+func (s *Store) queryRows(query string, args ...interface{}) ([]string, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []string
+	for rows.Next() {
+		var v string
+		if err := rows.Scan(&v); err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	return out, rows.Err()
+}
+
+func (s *Store) PutTemplate(name, html string) error {
+	_, err := s.db.Exec(`INSERT INTO templates(name, html, uploaded_at) VALUES (?, ?, ?)
+		ON CONFLICT(name) DO UPDATE SET html = excluded.html, uploaded_at = excluded.uploaded_at`,
+		name, html, time.Now().UnixNano())
+	return err
+}
+
+func (s *Store) GetTemplate(name string) (string, bool, error) {
+	row := s.db.QueryRow(`SELECT html FROM templates WHERE name = ?`, name)
+	var html string
+	if err := row.Scan(&html); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", false, nil
+		}
+		return "", false, err
+	}
+	return html, true, nil
+}
+
+func (s *Store) ListTemplates() ([]string, error) {
+	rows, err := s.db.Query(`SELECT name FROM templates`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		out = append(out, name)
+	}
+	return out, rows.Err()
+}
+
+var _ store.Store = (*Store)(nil)
@@ -0,0 +1,60 @@
+package sqlite
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func openTestStore(t *testing.T) *Store {
+	t.Helper()
+	s, err := Open(filepath.Join(t.TempDir(), "pod.db"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+// TestInsertFormTrimsAndSkipsEmpty checks that InsertForm trims keys and
+// values and skips blank ones, as memory.Store's indexing does.
+func TestInsertFormTrimsAndSkipsEmpty(t *testing.T) {
+	s := openTestStore(t)
+
+	kv := map[string][]string{
+		" color ": {" red ", ""},
+		"":        {"ignored"},
+	}
+	if err := s.InsertForm("form_1", kv); err != nil {
+		t.Fatalf("InsertForm: %v", err)
+	}
+
+	matches, err := s.QueryForms(map[string][]string{"color": {"red"}})
+	if err != nil {
+		t.Fatalf("QueryForms: %v", err)
+	}
+	if len(matches) != 1 || matches[0] != "form_1" {
+		t.Errorf("QueryForms(color=red) = %v, want [form_1] (trimmed key/value should match)", matches)
+	}
+
+	if matches, err := s.QueryForms(map[string][]string{"": {"ignored"}}); err != nil || len(matches) != 0 {
+		t.Errorf("QueryForms(\"\"=ignored) = (%v, %v), want no matches (blank key/value must be skipped)", matches, err)
+	}
+}
+
+// TestNewSessionIDsAreUnique guards against the old timestamp+username
+// scheme, which collided for same-nanosecond guest sessions.
+func TestNewSessionIDsAreUnique(t *testing.T) {
+	s := openTestStore(t)
+
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		id, err := s.NewSession("")
+		if err != nil {
+			t.Fatalf("NewSession: %v", err)
+		}
+		if seen[id] {
+			t.Fatalf("NewSession produced duplicate id %q", id)
+		}
+		seen[id] = true
+	}
+}
@@ -0,0 +1,43 @@
+// Package store defines the persistence interface used by pod for users,
+// sessions, stored form submissions, and page templates. The sqlite
+// sub-package backs it with an on-disk database; the memory sub-package
+// keeps everything in process memory for tests.
+package store
+
+import "errors"
+
+var (
+	// ErrUserExists is returned by CreateUser when the username is taken.
+	ErrUserExists = errors.New("store: username already taken")
+)
+
+// User is the persisted record for a registered account.
+type User struct {
+	Username      string
+	Salt          []byte // random salt; hashed into legacy PassHash, and sent to the client as SaltB64 by appHandler
+	PassHash      []byte // "bcrypt$"-prefixed bcrypt hash, or legacy SHA‑256(salt‖password)
+	EncContextB64 string // base64-encoded AES-GCM ciphertext of the UI context
+}
+
+// Store is the persistence interface implemented by sqlite.Store and
+// memory.Store. All methods must be safe for concurrent use.
+type Store interface {
+	CreateUser(username string, salt, passHash []byte) error
+	GetUser(username string) (*User, bool, error)
+	UpdateUserContext(username, encCtxB64 string) error
+	UpdateUserPassword(username string, salt, passHash []byte) error
+
+	NewSession(username string) (sessionID string, err error)
+	LookupSession(sessionID string) (username string, ok bool, err error)
+	DeleteSession(sessionID string) error
+	DeleteSessionsForUser(username string) error
+
+	InsertForm(base string, kv map[string][]string) error
+	QueryForms(query map[string][]string) ([]string, error)
+
+	PutTemplate(name, html string) error
+	GetTemplate(name string) (html string, ok bool, err error)
+	ListTemplates() ([]string, error)
+
+	Close() error
+}